@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ProgressCallback reports drain progress so a caller can surface e.g.
+// "waiting on 3 connections, 2s remaining" to the user.
+type ProgressCallback func(active int, elapsed time.Duration)
+
+// GracefulServer wraps an *http.Server and tracks its live connections via
+// ConnState plus a wrapped net.Listener/net.Conn. ConnState alone isn't
+// enough: once a connection is hijacked (WebSocket/SSE/task-stream), the
+// server stops managing it and never reports another state transition for
+// it, so a bare http.Server.Shutdown - and a naive ConnState-only tracker -
+// both lose track of it the instant the handoff happens. Wrapping Accept
+// lets the hijacked connection's own Close() (called by the handler when the
+// stream actually ends) keep GracefulServer's count accurate, the same way
+// Gitea's graceful package does it.
+type GracefulServer struct {
+	*http.Server
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewGracefulServer wraps srv, chaining any ConnState callback srv already
+// had so existing behavior keeps working.
+func NewGracefulServer(srv *http.Server) *GracefulServer {
+	g := &GracefulServer{
+		Server: srv,
+		conns:  make(map[net.Conn]struct{}),
+	}
+	prev := srv.ConnState
+	srv.ConnState = func(c net.Conn, state http.ConnState) {
+		g.trackConn(c, state)
+		if prev != nil {
+			prev(c, state)
+		}
+	}
+	return g
+}
+
+// trackConn only ever adds to g.conns. Removal happens exclusively through
+// gracefulConn.Close, which fires whether net/http closes the connection
+// itself (the StateClosed case) or a handler closes it long after hijacking
+// - so there is exactly one place connections leave the tracked set.
+func (g *GracefulServer) trackConn(c net.Conn, state http.ConnState) {
+	if state != http.StateNew {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.conns[c] = struct{}{}
+}
+
+func (g *GracefulServer) removeConn(c net.Conn) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.conns, c)
+}
+
+// Serve wraps l so every accepted connection is tracked through hijacking,
+// then delegates to http.Server.Serve.
+func (g *GracefulServer) Serve(l net.Listener) error {
+	return g.Server.Serve(g.wrapListener(l))
+}
+
+// ServeTLS is Serve's TLS counterpart.
+func (g *GracefulServer) ServeTLS(l net.Listener, certFile, keyFile string) error {
+	return g.Server.ServeTLS(g.wrapListener(l), certFile, keyFile)
+}
+
+func (g *GracefulServer) wrapListener(l net.Listener) net.Listener {
+	return &gracefulListener{Listener: l, g: g}
+}
+
+type gracefulListener struct {
+	net.Listener
+	g *GracefulServer
+}
+
+func (l *gracefulListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &gracefulConn{Conn: c, g: l.g}, nil
+}
+
+// gracefulConn removes itself from its GracefulServer's tracked set on
+// Close, regardless of whether it's being closed by net/http (request done,
+// idle timeout, Shutdown) or by a handler that hijacked it and is only now
+// tearing down a long-lived stream.
+type gracefulConn struct {
+	net.Conn
+	g         *GracefulServer
+	closeOnce sync.Once
+}
+
+func (c *gracefulConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() { c.g.removeConn(c) })
+	return err
+}
+
+// ActiveConnections returns the number of connections currently tracked.
+func (g *GracefulServer) ActiveConnections() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.conns)
+}
+
+// Drain stops the server from accepting new connections, then polls
+// ActiveConnections until it reaches zero or ctx is done, calling progress
+// (if non-nil) on every poll.
+func (g *GracefulServer) Drain(ctx context.Context, progress ProgressCallback) error {
+	shutdownCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- g.Server.Shutdown(shutdownCtx) }()
+
+	start := time.Now()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		active := g.ActiveConnections()
+		if progress != nil {
+			progress(active, time.Since(start))
+		}
+		if active == 0 {
+			cancel()
+			return <-shutdownErr
+		}
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-shutdownErr
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Hammer forcibly closes any connections still tracked. Callers use this
+// after Drain's context expires with stragglers remaining.
+func (g *GracefulServer) Hammer() {
+	g.mu.Lock()
+	conns := make([]net.Conn, 0, len(g.conns))
+	for c := range g.conns {
+		conns = append(conns, c)
+	}
+	g.mu.Unlock()
+
+	// Close outside the lock: gracefulConn.Close calls back into
+	// removeConn, which takes g.mu itself.
+	for _, c := range conns {
+		c.Close()
+	}
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestGracefulServerTracksHijackedConn(t *testing.T) {
+	srv := &http.Server{}
+	g := NewGracefulServer(srv)
+
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+
+	conn := &gracefulConn{Conn: server, g: g}
+	g.trackConn(conn, http.StateNew)
+	if got := g.ActiveConnections(); got != 1 {
+		t.Fatalf("ActiveConnections() after StateNew = %d, want 1", got)
+	}
+
+	g.trackConn(conn, http.StateHijacked)
+	if got := g.ActiveConnections(); got != 1 {
+		t.Fatalf("ActiveConnections() after StateHijacked = %d, want 1 (hijacked conns must stay tracked)", got)
+	}
+
+	conn.Close()
+	if got := g.ActiveConnections(); got != 0 {
+		t.Fatalf("ActiveConnections() after Close = %d, want 0", got)
+	}
+}
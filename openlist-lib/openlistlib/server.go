@@ -2,14 +2,18 @@ package openlistlib
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,6 +26,8 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/server"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type LogCallback interface {
@@ -32,18 +38,76 @@ type Event interface {
 	OnStartError(t string, err string)
 	OnShutdown(t string)
 	OnProcessExit(code int)
+	// OnListening fires once a listener for scheme ("http", "https", "unix")
+	// is bound and accepting connections.
+	OnListening(scheme, addr string)
+	// OnDrainProgress fires periodically while draining scheme's server,
+	// reporting the remaining live connections and elapsed drain time.
+	OnDrainProgress(scheme string, active int, elapsedMs int64)
+	// OnRestart fires when a zero-downtime Restart begins, e.g. reason
+	// "manual" or "SIGUSR1".
+	OnRestart(reason string)
+	// OnConfigReload fires when a SIGHUP-triggered config Reload finishes;
+	// err is empty on success.
+	OnConfigReload(err string)
+	// OnSignal fires whenever the signal-handling goroutine observes a
+	// SIGINT/SIGTERM/SIGHUP/SIGUSR1.
+	OnSignal(name string)
 }
 
+// BaseEvent is a no-op Event implementation. Embed it in bindings so adding
+// new lifecycle events later doesn't break existing implementations.
+type BaseEvent struct{}
+
+func (BaseEvent) OnStartError(t string, err string)                          {}
+func (BaseEvent) OnShutdown(t string)                                        {}
+func (BaseEvent) OnProcessExit(code int)                                     {}
+func (BaseEvent) OnListening(scheme, addr string)                            {}
+func (BaseEvent) OnDrainProgress(scheme string, active int, elapsedMs int64) {}
+func (BaseEvent) OnRestart(reason string)                                    {}
+func (BaseEvent) OnConfigReload(err string)                                  {}
+func (BaseEvent) OnSignal(name string)                                       {}
+
 var event Event
 var logFormatter *internal.MyFormatter
 
 // 添加全局的quit channel用于信号处理
 var (
-	quitChannel chan os.Signal
-	serverMutex sync.Mutex
+	quitChannel     chan os.Signal
+	serverMutex     sync.Mutex
 	isServerRunning bool
 )
 
+// HammerTime is the max duration (in milliseconds) a SIGUSR1-triggered restart
+// will wait for lingering connections before forcibly closing them; Restart
+// and Shutdown use the timeout passed to them instead. Zero disables the
+// forced close, matching the default used by Gitea's graceful module.
+var HammerTime int64
+
+// pendingShutdownTimeout carries the caller-supplied timeout from Shutdown
+// into the signal-handling goroutine, since the actual shutdown happens
+// asynchronously once the SIGTERM/SIGINT is observed on quitChannel.
+var pendingShutdownTimeout int64
+
+const (
+	envListenFDs      = "LISTEN_FDS"
+	envListenAddrs    = "OPENLIST_LISTENER_ADDRS"
+	envSystemdPID     = "LISTEN_PID"
+	envSystemdFDNames = "LISTEN_FDNAMES"
+	envReadyFD        = "OPENLIST_READY_FD"
+	listenFDsStart    = 3
+)
+
+// inheritedListeners holds listeners adopted from a parent process, keyed by
+// scheme ("http", "https", "unix"). Entries are consumed (and removed) by
+// acquireListener/acquireUnixListener as Start() binds each server.
+var inheritedListeners = map[string]net.Listener{}
+
+// passedListeners snapshots every listener adoptInheritedListeners accepted,
+// for introspection via PassedListeners() after Start() has drained
+// inheritedListeners into the running servers.
+var passedListeners = map[string]net.Listener{}
+
 func Init(e Event, cb LogCallback) error {
 	event = e
 	cmd.Init()
@@ -58,21 +122,202 @@ func Init(e Event, cb LogCallback) error {
 		utils.Log.SetFormatter(logFormatter)
 		utils.Log.ExitFunc = event.OnProcessExit
 	}
-	
+
+	adoptInheritedListeners()
+	adoptReadyFD()
+
 	// 初始化信号处理
 	if quitChannel == nil {
 		quitChannel = make(chan os.Signal, 1)
-		signal.Notify(quitChannel, syscall.SIGINT, syscall.SIGTERM)
+		signal.Notify(quitChannel, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1)
 		utils.Log.Println("Signal handler initialized")
 	}
-	
+
 	return nil
 }
 
-var httpSrv, httpsSrv, unixSrv *http.Server
+// adoptInheritedListeners adopts listener fds handed off by a parent
+// process instead of binding fresh sockets, falling back to a normal bind
+// in Start() when neither protocol below is present. Two protocols are
+// recognized:
+//   - our own Restart handoff: LISTEN_FDS + OPENLIST_LISTENER_ADDRS
+//     ("scheme=addr" pairs), used for the fork/exec zero-downtime restart;
+//   - systemd/launchd socket activation: LISTEN_PID + LISTEN_FDS +
+//     LISTEN_FDNAMES (colon-separated names, one per fd), used when OpenList
+//     is launched from a systemd .socket unit or launchd so it can bind
+//     privileged ports (80/443) without running as root.
+func adoptInheritedListeners() {
+	fdsStr := os.Getenv(envListenFDs)
+	if fdsStr == "" {
+		return
+	}
+	n, err := strconv.Atoi(fdsStr)
+	if err != nil || n <= 0 {
+		utils.Log.Warnf("ignoring malformed %s=%q", envListenFDs, fdsStr)
+		return
+	}
+
+	var names []string
+	switch {
+	case os.Getenv(envListenAddrs) != "":
+		for _, pair := range strings.Split(os.Getenv(envListenAddrs), ",") {
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) == 2 {
+				names = append(names, parts[0])
+			}
+		}
+	case os.Getenv(envSystemdFDNames) != "":
+		pid, err := strconv.Atoi(os.Getenv(envSystemdPID))
+		if err != nil || pid != os.Getpid() {
+			utils.Log.Warnf("ignoring %s: %s does not match this process", envSystemdFDNames, envSystemdPID)
+			return
+		}
+		names = strings.Split(os.Getenv(envSystemdFDNames), ":")
+	default:
+		return
+	}
+
+	for i := 0; i < n && i < len(names); i++ {
+		scheme := names[i]
+		fd := listenFDsStart + i
+		file := os.NewFile(uintptr(fd), scheme)
+		l, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			utils.Log.Errorf("failed to adopt inherited %s listener: %+v", scheme, err)
+			continue
+		}
+		inheritedListeners[scheme] = l
+		passedListeners[scheme] = l
+	}
+	if len(inheritedListeners) > 0 {
+		utils.Log.Infof("adopted %d listener(s) from %s", len(inheritedListeners), envListenFDs)
+	}
+}
+
+// readyFile is the write end of the readiness pipe a parent process handed
+// us via OPENLIST_READY_FD during Restart. nil unless this process was
+// started that way.
+var readyFile *os.File
+
+// adoptReadyFD opens the fd named in OPENLIST_READY_FD, if set, so
+// signalReady can later tell the parent that started us (via Restart) that
+// our listeners are up.
+func adoptReadyFD() {
+	fdStr := os.Getenv(envReadyFD)
+	if fdStr == "" {
+		return
+	}
+	fd, err := strconv.Atoi(fdStr)
+	if err != nil {
+		utils.Log.Warnf("ignoring malformed %s=%q", envReadyFD, fdStr)
+		return
+	}
+	readyFile = os.NewFile(uintptr(fd), "ready")
+}
+
+// signalReady tells the parent process that started us (via Restart) that
+// our listeners are up, unblocking its Restart wait. A no-op when this
+// process wasn't started via Restart.
+func signalReady() {
+	signalResult(true)
+}
+
+// signalFailed tells the parent process that started us that one or more
+// listeners failed to bind, so it can abort the handover instead of
+// draining itself for a child that never came up.
+func signalFailed() {
+	signalResult(false)
+}
+
+// signalResult writes a single byte (1 for ready, 0 for failed) to and
+// closes the readiness fd adopted by adoptReadyFD. A no-op when this
+// process wasn't started via Restart.
+func signalResult(ok bool) {
+	if readyFile == nil {
+		return
+	}
+	b := byte(0)
+	if ok {
+		b = 1
+	}
+	_, _ = readyFile.Write([]byte{b})
+	readyFile.Close()
+	readyFile = nil
+}
+
+// PassedListeners returns the listeners this process inherited from a parent
+// via Restart or systemd/launchd socket activation, keyed by scheme. It
+// remains populated after Start() has adopted the listeners into the
+// running servers, for diagnostics/introspection.
+func PassedListeners() map[string]net.Listener {
+	out := make(map[string]net.Listener, len(passedListeners))
+	for k, v := range passedListeners {
+		out[k] = v
+	}
+	return out
+}
+
+// acquireListener returns the inherited TCP listener for scheme if Start()
+// was handed one via Restart/socket-activation, otherwise it binds a fresh
+// listener on addr.
+func acquireListener(scheme, addr string) (*net.TCPListener, error) {
+	if l, ok := inheritedListeners[scheme]; ok {
+		delete(inheritedListeners, scheme)
+		tl, ok := l.(*net.TCPListener)
+		if !ok {
+			return nil, fmt.Errorf("inherited %s listener is not a TCP listener", scheme)
+		}
+		return tl, nil
+	}
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return net.ListenTCP("tcp", tcpAddr)
+}
+
+// acquireUnixListener is the unix-socket counterpart of acquireListener.
+func acquireUnixListener(path string) (*net.UnixListener, error) {
+	if l, ok := inheritedListeners["unix"]; ok {
+		delete(inheritedListeners, "unix")
+		ul, ok := l.(*net.UnixListener)
+		if !ok {
+			return nil, errors.New("inherited unix listener is not a unix socket")
+		}
+		return ul, nil
+	}
+	return net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+}
+
+var (
+	httpSrv, httpsSrv, unixSrv  *internal.GracefulServer
+	httpListener, httpsListener *net.TCPListener
+	unixListener                *net.UnixListener
+
+	// router is the shared gin handler behind every listener, so Reload can
+	// rebind a listener without rebuilding routes.
+	router *gin.Engine
+
+	// currentCert holds the HTTPS certificate served via TLSConfig.GetCertificate,
+	// so Reload can hot-swap it (cert renewal) without restarting the listener.
+	currentCert atomic.Pointer[tls.Certificate]
+)
+
+func serve(t string, srv *internal.GracefulServer, l net.Listener) {
+	err := srv.Serve(l)
+	if err != nil && err != http.ErrServerClosed {
+		event.OnStartError(t, err.Error())
+	} else {
+		event.OnShutdown(t)
+	}
+}
 
-func listenAndServe(t string, srv *http.Server) {
-	err := srv.ListenAndServe()
+// serveTLS is serve's HTTPS counterpart: the cert/key are served via
+// TLSConfig.GetCertificate (see loadCertificate), so both arguments here are
+// intentionally empty.
+func serveTLS(t string, srv *internal.GracefulServer, l net.Listener) {
+	err := srv.ServeTLS(l, "", "")
 	if err != nil && err != http.ErrServerClosed {
 		event.OnStartError(t, err.Error())
 	} else {
@@ -80,10 +325,250 @@ func listenAndServe(t string, srv *http.Server) {
 	}
 }
 
+// loadCertificate reads conf.Conf.Scheme's cert/key files and stores them in
+// currentCert, from which the HTTPS server's TLSConfig.GetCertificate serves
+// them. Calling this again after Reload hot-swaps the certificate without
+// dropping connections on the still-bound listener.
+func loadCertificate() error {
+	cert, err := tls.LoadX509KeyPair(conf.Conf.Scheme.CertFile, conf.Conf.Scheme.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	currentCert.Store(&cert)
+	return nil
+}
+
+// H2Config holds the HTTP/2 knobs startHTTP/startHTTPS apply. It lives here
+// rather than on conf.Conf.Scheme because that struct belongs to the
+// OpenList core module this package only imports - it isn't ours to extend.
+// EnableH2C additionally accepts cleartext HTTP/2 (h2c) on the plain HTTP
+// listener; MaxConcurrentStreams/IdleTimeout tune the HTTP/2 server
+// configured on the HTTPS listener.
+var H2Config = struct {
+	EnableH2C            bool
+	MaxConcurrentStreams uint32
+	IdleTimeout          time.Duration
+}{}
+
+// startHTTP binds and serves the HTTP listener described by conf.Conf.Scheme,
+// adopting an inherited fd if one was handed off via Restart/socket-activation.
+// When H2Config.EnableH2C is set, the handler also speaks HTTP/2 in
+// cleartext (h2c), so a single plaintext connection can multiplex requests
+// instead of being capped at HTTP/1.1's 6-connections-per-origin limit.
+// startHTTP reports whether the listener came up; false means
+// event.OnStartError already fired. A disabled listener (HttpPort == -1)
+// counts as success, since there was nothing to bind.
+func startHTTP() bool {
+	if conf.Conf.Scheme.HttpPort == -1 {
+		return true
+	}
+	httpBase := fmt.Sprintf("%s:%d", conf.Conf.Scheme.Address, conf.Conf.Scheme.HttpPort)
+	utils.Log.Infof("start HTTP server @ %s", httpBase)
+	l, err := acquireListener("http", httpBase)
+	if err != nil {
+		event.OnStartError("http", err.Error())
+		return false
+	}
+	httpListener = l
+	var handler http.Handler = router
+	if H2Config.EnableH2C {
+		handler = h2c.NewHandler(router, &http2.Server{})
+	}
+	httpSrv = internal.NewGracefulServer(&http.Server{Handler: handler})
+	event.OnListening("http", httpBase)
+	go func() {
+		serve("http", httpSrv, httpListener)
+		httpSrv = nil
+		httpListener = nil
+	}()
+	return true
+}
+
+// startHTTPS binds and serves the HTTPS listener described by conf.Conf.Scheme.
+// http2.ConfigureServer opts the listener into HTTP/2 (Go only negotiates h2
+// automatically via net/http's own ALPN setup, which doesn't let us tune
+// MaxConcurrentStreams/IdleTimeout), which matters for the highly
+// multiplexed downloads/uploads OpenList's WebDAV and file APIs generate
+// from a single client.
+// startHTTPS reports whether the listener came up; false means
+// event.OnStartError already fired. A disabled listener (HttpsPort == -1)
+// counts as success, since there was nothing to bind.
+func startHTTPS() bool {
+	if conf.Conf.Scheme.HttpsPort == -1 {
+		return true
+	}
+	if err := loadCertificate(); err != nil {
+		event.OnStartError("https", err.Error())
+		return false
+	}
+	httpsBase := fmt.Sprintf("%s:%d", conf.Conf.Scheme.Address, conf.Conf.Scheme.HttpsPort)
+	utils.Log.Infof("start HTTPS server @ %s", httpsBase)
+	l, err := acquireListener("https", httpsBase)
+	if err != nil {
+		event.OnStartError("https", err.Error())
+		return false
+	}
+	httpsListener = l
+	srv := &http.Server{
+		Handler: router,
+		TLSConfig: &tls.Config{
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				return currentCert.Load(), nil
+			},
+		},
+	}
+	err = http2.ConfigureServer(srv, &http2.Server{
+		MaxConcurrentStreams: H2Config.MaxConcurrentStreams,
+		IdleTimeout:          H2Config.IdleTimeout,
+	})
+	if err != nil {
+		utils.Log.Errorf("failed to configure HTTP/2 on HTTPS server: %+v", err)
+	}
+	httpsSrv = internal.NewGracefulServer(srv)
+	event.OnListening("https", httpsBase)
+	go func() {
+		serveTLS("https", httpsSrv, httpsListener)
+		httpsSrv = nil
+		httpsListener = nil
+	}()
+	return true
+}
+
+// startUnix binds and serves the unix-socket listener described by
+// conf.Conf.Scheme. It reports whether the listener came up; false means
+// event.OnStartError already fired. A disabled listener (UnixFile == "")
+// counts as success, since there was nothing to bind.
+func startUnix() bool {
+	if conf.Conf.Scheme.UnixFile == "" {
+		return true
+	}
+	utils.Log.Infof("start unix server @ %s", conf.Conf.Scheme.UnixFile)
+	l, err := acquireUnixListener(conf.Conf.Scheme.UnixFile)
+	if err != nil {
+		event.OnStartError("unix", err.Error())
+		return false
+	}
+	unixListener = l
+	unixSrv = internal.NewGracefulServer(&http.Server{Handler: router})
+	// set socket file permission
+	mode, err := strconv.ParseUint(conf.Conf.Scheme.UnixFilePerm, 8, 32)
+	if err != nil {
+		utils.Log.Errorf("failed to parse socket file permission: %+v", err)
+	} else if err := os.Chmod(conf.Conf.Scheme.UnixFile, os.FileMode(mode)); err != nil {
+		utils.Log.Errorf("failed to chmod socket file: %+v", err)
+	}
+	event.OnListening("unix", conf.Conf.Scheme.UnixFile)
+	go func() {
+		serve("unix", unixSrv, unixListener)
+		unixSrv = nil
+		unixListener = nil
+	}()
+	return true
+}
+
+// reloadDrainTimeout bounds how long stopListener will wait for a
+// listener's in-flight connections to drain during Reload before hammering
+// them closed. Reload runs synchronously inside the single
+// signal-handling goroutine with serverMutex held, so - unlike HammerTime,
+// which may be disabled to let Restart/Shutdown wait indefinitely - this
+// must never be unbounded: a long-lived connection (a big download, an
+// open WebDAV stream) would otherwise wedge that goroutine forever, taking
+// every future SIGTERM/SIGINT/SIGUSR1/SIGHUP down with it.
+const reloadDrainTimeout = 10 * time.Second
+
+// stopListener drains and tears down the given scheme's listener, if bound.
+func stopListener(t string) {
+	ctx, cancel := context.WithTimeout(context.Background(), reloadDrainTimeout)
+	defer cancel()
+	switch t {
+	case "http":
+		if httpSrv != nil {
+			drainServer("http", httpSrv, ctx)
+			httpSrv = nil
+			httpListener = nil
+		}
+	case "https":
+		if httpsSrv != nil {
+			drainServer("https", httpsSrv, ctx)
+			httpsSrv = nil
+			httpsListener = nil
+		}
+	case "unix":
+		if unixSrv != nil {
+			drainServer("unix", unixSrv, ctx)
+			unixSrv = nil
+			unixListener = nil
+		}
+	}
+}
+
+// reconcileListeners compares the Scheme block before and after a Reload and
+// restarts only the listeners whose address (or, for the unix socket, file
+// permission) actually changed. When the HTTPS listener stays put, its
+// cert/key are re-read from disk on every Reload and hot-swapped in place via
+// loadCertificate, so a certificate renewed at the same path is picked up.
+func reconcileListeners(prev, next conf.Scheme) error {
+	if prev.HttpPort != next.HttpPort || prev.Address != next.Address {
+		stopListener("http")
+		startHTTP()
+	}
+
+	if prev.HttpsPort != next.HttpsPort || prev.Address != next.Address {
+		stopListener("https")
+		startHTTPS()
+	} else if next.HttpsPort != -1 {
+		if err := loadCertificate(); err != nil {
+			return err
+		}
+		utils.Log.Println("https certificate hot-swapped")
+	}
+
+	if prev.UnixFile != next.UnixFile || prev.UnixFilePerm != next.UnixFilePerm {
+		stopListener("unix")
+		startUnix()
+	}
+
+	return nil
+}
+
+// Reload re-reads conf.Conf from disk and reconciles listeners, the HTTPS
+// certificate and storage/task state to match, without restarting the
+// process or dropping connections on listeners whose address didn't change.
+// Wired to SIGHUP so cert renewal (Let's Encrypt, mkcert, ...) doesn't
+// require a full Restart.
+func Reload() error {
+	serverMutex.Lock()
+	defer serverMutex.Unlock()
+
+	if !isServerRunning {
+		return errors.New("server is not running")
+	}
+
+	prevScheme := conf.Conf.Scheme
+	if err := conf.InitConf(); err != nil {
+		event.OnConfigReload(err.Error())
+		return fmt.Errorf("failed to reload config: %w", err)
+	}
+
+	if err := reconcileListeners(prevScheme, conf.Conf.Scheme); err != nil {
+		event.OnConfigReload(err.Error())
+		return err
+	}
+
+	if err := bootstrap.Reload(); err != nil {
+		event.OnConfigReload(err.Error())
+		return err
+	}
+
+	event.OnConfigReload("")
+	utils.Log.Println("Configuration reloaded")
+	return nil
+}
+
 func IsRunning(t string) bool {
 	serverMutex.Lock()
 	defer serverMutex.Unlock()
-	
+
 	switch t {
 	case "http":
 		return httpSrv != nil
@@ -109,7 +594,7 @@ func Start() {
 	}
 	isServerRunning = true
 	serverMutex.Unlock()
-	
+
 	if conf.Conf.DelayedStart != 0 {
 		utils.Log.Infof("delayed start for %d seconds", conf.Conf.DelayedStart)
 		time.Sleep(time.Duration(conf.Conf.DelayedStart) * time.Second)
@@ -120,142 +605,262 @@ func Start() {
 	if !flags.Debug && !flags.Dev {
 		gin.SetMode(gin.ReleaseMode)
 	}
-	r := gin.New()
-	r.Use(gin.LoggerWithWriter(log.StandardLogger().Out), gin.RecoveryWithWriter(log.StandardLogger().Out))
-	server.Init(r)
+	router = gin.New()
+	router.Use(gin.LoggerWithWriter(log.StandardLogger().Out), gin.RecoveryWithWriter(log.StandardLogger().Out))
+	server.Init(router)
 
-	if conf.Conf.Scheme.HttpPort != -1 {
-		httpBase := fmt.Sprintf("%s:%d", conf.Conf.Scheme.Address, conf.Conf.Scheme.HttpPort)
-		utils.Log.Infof("start HTTP server @ %s", httpBase)
-		httpSrv = &http.Server{Addr: httpBase, Handler: r}
-		go func() {
-			listenAndServe("http", httpSrv)
-			httpSrv = nil
-		}()
-	}
-	if conf.Conf.Scheme.HttpsPort != -1 {
-		httpsBase := fmt.Sprintf("%s:%d", conf.Conf.Scheme.Address, conf.Conf.Scheme.HttpsPort)
-		utils.Log.Infof("start HTTPS server @ %s", httpsBase)
-		httpsSrv = &http.Server{Addr: httpsBase, Handler: r}
-		go func() {
-			listenAndServe("https", httpsSrv)
-			httpsSrv = nil
-		}()
+	httpOK := startHTTP()
+	httpsOK := startHTTPS()
+	unixOK := startUnix()
+	if httpOK && httpsOK && unixOK {
+		signalReady()
+	} else {
+		// One or more listeners failed to bind: tell a parent that's waiting
+		// on us via Restart to abort the handover instead of draining itself
+		// for a child that never actually came up.
+		signalFailed()
 	}
-	if conf.Conf.Scheme.UnixFile != "" {
-		utils.Log.Infof("start unix server @ %s", conf.Conf.Scheme.UnixFile)
-		unixSrv = &http.Server{Handler: r}
-		go func() {
-			listener, err := net.Listen("unix", conf.Conf.Scheme.UnixFile)
-			if err != nil {
-				//utils.Log.Fatalf("failed to listenAndServe unix: %+v", err)
-				event.OnStartError("unix", err.Error())
-			} else {
-				// set socket file permission
-				mode, err := strconv.ParseUint(conf.Conf.Scheme.UnixFilePerm, 8, 32)
-				if err != nil {
-					utils.Log.Errorf("failed to parse socket file permission: %+v", err)
-				} else {
-					err = os.Chmod(conf.Conf.Scheme.UnixFile, os.FileMode(mode))
-					if err != nil {
-						utils.Log.Errorf("failed to chmod socket file: %+v", err)
-					}
+
+	// 启动信号等待goroutine，模拟原本的main函数行为
+	go func() {
+		utils.Log.Println("Signal handler started, waiting for SIGTERM/SIGINT/SIGHUP/SIGUSR1...")
+		for sig := range quitChannel {
+			event.OnSignal(sig.String())
+			switch sig {
+			case syscall.SIGHUP:
+				utils.Log.Println("Received SIGHUP, reloading configuration...")
+				if err := Reload(); err != nil {
+					utils.Log.Errorf("reload failed: %+v", err)
 				}
-				err = unixSrv.Serve(listener)
-				if err != nil && err != http.ErrServerClosed {
-					event.OnStartError("unix", err.Error())
+				continue
+			case syscall.SIGUSR1:
+				utils.Log.Println("Received SIGUSR1, restarting...")
+				if err := restart(HammerTime, "SIGUSR1"); err != nil {
+					utils.Log.Errorf("restart failed: %+v", err)
 				}
+				continue
 			}
+			utils.Log.Println("Received shutdown signal, initiating graceful shutdown...")
+			performGracefulShutdown(pendingShutdownTimeout)
+			return
+		}
+	}()
+}
 
-			unixSrv = nil
-		}()
+// listenerFiles collects the underlying *os.File for every bound listener,
+// alongside a matching "scheme=addr" descriptor, for handing off to a child
+// process during Restart.
+func listenerFiles() ([]*os.File, []string, error) {
+	var files []*os.File
+	var names []string
+
+	if httpListener != nil {
+		f, err := httpListener.File()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to dup http listener: %w", err)
+		}
+		files = append(files, f)
+		names = append(names, fmt.Sprintf("http=%s", httpListener.Addr().String()))
 	}
-	
-	// 启动信号等待goroutine，模拟原本的main函数行为
-	go func() {
-		utils.Log.Println("Signal handler started, waiting for SIGTERM/SIGINT...")
-		<-quitChannel // 等待信号
-		utils.Log.Println("Received shutdown signal, initiating graceful shutdown...")
-		performGracefulShutdown()
-	}()
+	if httpsListener != nil {
+		f, err := httpsListener.File()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to dup https listener: %w", err)
+		}
+		files = append(files, f)
+		names = append(names, fmt.Sprintf("https=%s", httpsListener.Addr().String()))
+	}
+	if unixListener != nil {
+		f, err := unixListener.File()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to dup unix listener: %w", err)
+		}
+		files = append(files, f)
+		names = append(names, fmt.Sprintf("unix=%s", conf.Conf.Scheme.UnixFile))
+	}
+
+	return files, names, nil
 }
 
-func shutdown(srv *http.Server, timeout time.Duration) error {
-	if srv == nil {
-		return nil
+// Restart performs a zero-downtime restart: it forks/execs a copy of the
+// current binary, handing it the already-bound listener fds via
+// LISTEN_FDS/OPENLIST_LISTENER_ADDRS so it can adopt them instead of binding
+// fresh sockets, waits for the child to signal readiness, then drains this
+// process within the given timeout (milliseconds). This lets long-lived
+// deployments reload config/certs without dropping in-flight connections.
+func Restart(timeout int64) error {
+	return restart(timeout, "manual")
+}
+
+func restart(timeout int64, reason string) error {
+	// Snapshot the listener fds under serverMutex, the same lock Reload
+	// holds for its entire run: Reload's stopListener/startHTTP etc. close
+	// and replace httpListener/httpsListener/unixListener, so reading them
+	// here without the lock could hand the forked child a stale or
+	// already-closed fd if a SIGHUP reload races with this restart.
+	serverMutex.Lock()
+	running := isServerRunning
+	var files []*os.File
+	var names []string
+	var err error
+	if running {
+		files, names, err = listenerFiles()
+	}
+	serverMutex.Unlock()
+	if !running {
+		return errors.New("server is not running")
+	}
+	if err != nil {
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	event.OnRestart(reason)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to resolve current executable: %w", err)
+	}
 
-	err := srv.Shutdown(ctx)
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create readiness pipe: %w", err)
+	}
+	defer readyR.Close()
+
+	child := exec.Command(executable, os.Args[1:]...)
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	// readyW lands after the listener fds in ExtraFiles, so its fd number in
+	// the child is listenFDsStart+len(files); tell the child that via env so
+	// it knows which fd to write+close once its listeners are up.
+	readyFD := listenFDsStart + len(files)
+	child.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%d", envListenFDs, len(files)),
+		fmt.Sprintf("%s=%s", envListenAddrs, strings.Join(names, ",")),
+		fmt.Sprintf("%s=%d", envReadyFD, readyFD),
+	)
+	child.ExtraFiles = append(append([]*os.File{}, files...), readyW)
+
+	if err := child.Start(); err != nil {
+		readyW.Close()
+		return fmt.Errorf("failed to fork/exec %s: %w", executable, err)
+	}
+	readyW.Close()
+
+	// ready carries the single byte signalReady/signalFailed wrote in the
+	// child: 1 means every listener came up, 0 (or the pipe closing
+	// without a byte, e.g. the child crashed before calling either) means it
+	// didn't. Either way we stop waiting the moment the child tells us
+	// something, rather than always sitting out the full timeout.
+	ready := make(chan bool)
+	go func() {
+		buf := make([]byte, 1)
+		n, _ := readyR.Read(buf)
+		ready <- n == 1 && buf[0] == 1
+	}()
 
-	return err
+	select {
+	case ok := <-ready:
+		if !ok {
+			return fmt.Errorf("restart: child pid %d failed to start, aborting handover", child.Process.Pid)
+		}
+		utils.Log.Infof("restart: child pid %d is ready, draining current process", child.Process.Pid)
+	case <-time.After(30 * time.Second):
+		utils.Log.Warn("restart: timed out waiting for child readiness, proceeding with shutdown anyway")
+	}
+
+	performGracefulShutdown(timeout)
+	return nil
+}
+
+// drainServer stops t from accepting new connections and waits, via
+// GracefulServer.Drain, for in-flight connections (including hijacked
+// WebSocket/task-stream ones a bare http.Server.Shutdown can't see) to
+// finish. Stragglers still around when ctx expires are force-closed with
+// Hammer instead of leaking past the caller's timeout.
+func drainServer(t string, srv *internal.GracefulServer, ctx context.Context) {
+	err := srv.Drain(ctx, func(active int, elapsed time.Duration) {
+		event.OnDrainProgress(t, active, elapsed.Milliseconds())
+		if active > 0 {
+			utils.Log.Infof("%s: waiting on %d connection(s), %s elapsed", t, active, elapsed.Round(time.Second))
+		}
+	})
+	if err != nil {
+		utils.Log.Warnf("%s server drain incomplete (%v), hammering %d remaining connection(s)", t, err, srv.ActiveConnections())
+		srv.Hammer()
+	} else {
+		utils.Log.Println(t, "server shutdown completed")
+	}
 }
 
 // performGracefulShutdown 执行优雅关闭，模拟原本server.go中的逻辑
-func performGracefulShutdown() {
+func performGracefulShutdown(timeoutMs int64) {
 	serverMutex.Lock()
 	defer serverMutex.Unlock()
-	
+
 	if !isServerRunning {
 		utils.Log.Println("Server is not running, nothing to shutdown")
 		return
 	}
-	
+
 	utils.Log.Println("Performing graceful shutdown...")
-	
+
 	// 执行清理任务
 	cmd.Release()
-	
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	// timeoutMs <= 0 (e.g. the HammerTime default) means wait indefinitely
+	// for connections to drain and never forcibly close them, matching
+	// HammerTime's documented semantics and the Gitea pattern it follows -
+	// it must NOT silently fall back to a short default, which would
+	// force-close connections exactly when the caller asked not to.
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeoutMs > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeoutMs)*time.Millisecond)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
 	defer cancel()
-	
+
 	var wg sync.WaitGroup
-	
+
 	if httpSrv != nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := httpSrv.Shutdown(ctx); err != nil {
-				utils.Log.Error("HTTP server shutdown err: ", err)
-			} else {
-				utils.Log.Println("HTTP server shutdown completed")
-			}
+			drainServer("http", httpSrv, ctx)
 			httpSrv = nil
 		}()
 	}
-	
+
 	if httpsSrv != nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := httpsSrv.Shutdown(ctx); err != nil {
-				utils.Log.Error("HTTPS server shutdown err: ", err)
-			} else {
-				utils.Log.Println("HTTPS server shutdown completed")
-			}
+			drainServer("https", httpsSrv, ctx)
 			httpsSrv = nil
 		}()
 	}
-	
+
 	if unixSrv != nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := unixSrv.Shutdown(ctx); err != nil {
-				utils.Log.Error("Unix server shutdown err: ", err)
-			} else {
-				utils.Log.Println("Unix server shutdown completed")
-			}
+			drainServer("unix", unixSrv, ctx)
 			unixSrv = nil
 		}()
 	}
-	
+
 	wg.Wait()
 	isServerRunning = false
 	utils.Log.Println("Graceful shutdown completed")
-	
+
 	// 通知Android端关闭已完成
 	if event != nil {
 		event.OnShutdown("graceful")
@@ -265,16 +870,18 @@ func performGracefulShutdown() {
 // Shutdown 现在真正发送SIGTERM信号来触发优雅关闭
 func Shutdown(timeout int64) (err error) {
 	utils.Log.Println("Shutdown requested - sending SIGTERM signal...")
-	
+
 	serverMutex.Lock()
 	running := isServerRunning
 	serverMutex.Unlock()
-	
+
 	if !running {
 		utils.Log.Println("Server is not running")
 		return nil
 	}
-	
+
+	pendingShutdownTimeout = timeout
+
 	// 发送SIGTERM信号到我们自己的signal channel
 	// 这会触发在Start()中启动的信号等待goroutine
 	select {
@@ -282,29 +889,29 @@ func Shutdown(timeout int64) (err error) {
 		utils.Log.Println("SIGTERM signal sent successfully")
 	default:
 		utils.Log.Println("Signal channel is full or closed, performing direct shutdown")
-		performGracefulShutdown()
+		performGracefulShutdown(timeout)
 	}
-	
+
 	// 等待关闭完成，最多等待指定的超时时间
 	maxWait := time.Duration(timeout) * time.Millisecond
 	if maxWait < 100*time.Millisecond {
 		maxWait = 5 * time.Second // 默认5秒超时
 	}
-	
+
 	waitStart := time.Now()
 	for time.Since(waitStart) < maxWait {
 		serverMutex.Lock()
 		running := isServerRunning
 		serverMutex.Unlock()
-		
+
 		if !running {
 			utils.Log.Println("Shutdown completed successfully")
 			return nil
 		}
-		
+
 		time.Sleep(100 * time.Millisecond)
 	}
-	
+
 	utils.Log.Println("Shutdown timeout reached, but process may still be completing")
 	return nil
 }